@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/AmesianX/rover/rover"
+	"github.com/dustin/go-humanize"
+)
+
+// a line of a -batch file: a file to download from a zip, and where to
+type batchJob struct {
+	line       int
+	url        string
+	remoteFile string
+	localFile  string
+	outputFile string // localFile within -d, once that's known
+}
+
+// returns the job's URL with any password in it masked, for printing
+func (job batchJob) redactedURL() string {
+	u, err := url.Parse(job.url)
+
+	if err != nil {
+		return job.url
+	}
+
+	return u.Redacted()
+}
+
+// reads a -batch file, where each line is a URL, the name of a file in the
+// zip there and the local file to download it to, separated by tabs. the
+// local file may be left out to use the base name of the remote file. blank
+// lines and lines starting with # are ignored
+func readBatch(name string) ([]batchJob, error) {
+	input, err := os.Open(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer input.Close()
+
+	var jobs []batchJob
+
+	scanner := bufio.NewScanner(input)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("line %d: expected a URL, remote file and local file separated by tabs", line)
+		}
+
+		job := batchJob{line: line, url: fields[0], remoteFile: fields[1], localFile: path.Base(fields[1])}
+
+		if len(fields) == 3 && fields[2] != "" {
+			job.localFile = fields[2]
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, scanner.Err()
+}
+
+// downloads every file in jobs, -j at a time, carrying on past any which fail.
+// each file is reported on stderr, followed by a summary, though -q leaves
+// only the failures. returns the exit code of the first to fail, or 0 if none
+// did
+func runBatch(ctx context.Context, jobs []batchJob) int {
+	var mu sync.Mutex
+	var extracted, extractedBytes uint64
+
+	status := 0
+
+	failed := func(job batchJob, err error) {
+		fmt.Fprintf(os.Stderr, "\rUnable to extract %s from %s (line %d): %v\n", job.remoteFile, job.redactedURL(), job.line, err)
+
+		if status == 0 {
+			status = exitStatus(err)
+		}
+	}
+
+	// whether to overwrite each file is settled one at a time before any are
+	// downloaded, so questions on the terminal don't get each other's answers
+	var pending []batchJob
+
+	for _, job := range jobs {
+		job.outputFile = filepath.Join(outputDir, filepath.FromSlash(job.localFile))
+
+		// nothing is overwritten by a dry run, so there's no need to ask
+		if !dryRun {
+			if ok, err := mayOverwrite(job.outputFile); err != nil {
+				failed(job, err)
+				continue
+			} else if !ok {
+				failed(job, localError{fmt.Errorf("not replacing %s", job.outputFile)})
+				continue
+			}
+		}
+
+		pending = append(pending, job)
+	}
+
+	queue := make(chan batchJob)
+
+	var wg sync.WaitGroup
+
+	n := workers
+
+	if n > len(pending) {
+		n = len(pending)
+	}
+
+	// several files at once share a single progress line
+	if n > 1 {
+		overall = &overallProgress{files: len(pending)}
+		defer func() { overall = nil }()
+	}
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for job := range queue {
+				written, err := runBatchJob(ctx, job)
+
+				mu.Lock()
+
+				if errors.Is(err, context.Canceled) {
+					// already reported
+				} else if err != nil {
+					failed(job, err)
+				} else {
+					if !quiet {
+						fmt.Fprintf(os.Stderr, "\rExtracted %s from %s to %s\n", job.remoteFile, job.redactedURL(), job.localFile)
+					}
+
+					extracted++
+					extractedBytes += written
+				}
+
+				mu.Unlock()
+
+				if overall != nil {
+					overall.finish()
+				}
+			}
+		}()
+	}
+
+	for _, job := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+
+		queue <- job
+	}
+
+	close(queue)
+	wg.Wait()
+
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "\rExtracted %d of %d files (%s)\n", extracted, len(jobs), humanize.Bytes(extractedBytes))
+	}
+
+	if ctx.Err() != nil {
+		return exitFailure
+	}
+
+	return status
+}
+
+// downloads the file a line of a -batch file asks for to job.outputFile,
+// returning the number of bytes written
+func runBatchJob(ctx context.Context, job batchJob) (uint64, error) {
+	archive, err := rover.OpenContext(ctx, job.url, archiveOptions())
+
+	if err != nil {
+		return 0, err
+	}
+
+	files, err := archive.Find(job.remoteFile)
+
+	var ambiguous *rover.AmbiguousError
+
+	if errors.As(err, &ambiguous) {
+		files, err = ambiguous.Matches[:1], nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(files) > 1 {
+		return 0, fmt.Errorf("%s matches %d files", job.remoteFile, len(files))
+	}
+
+	if files[0].Encrypted {
+		return 0, rover.ErrEncrypted
+	}
+
+	if !dryRun {
+		if err := os.MkdirAll(filepath.Dir(job.outputFile), 0755); err != nil {
+			return 0, err
+		}
+	}
+
+	return extractFile(archive, files[0], job.outputFile)
+}
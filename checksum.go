@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// the hash algorithms usable with -checksum and -digest
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// parses a checksum of the form algorithm:hex, returning a constructor for
+// the algorithm's hash along with the expected sum
+func parseChecksum(checksum string) (func() hash.Hash, []byte, error) {
+	parts := strings.SplitN(checksum, ":", 2)
+
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("checksum must be of the form algorithm:hex, e.g. sha256:%x", sha256.Sum256(nil))
+	}
+
+	newHash, ok := checksumAlgorithms[strings.ToLower(parts[0])]
+
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown checksum algorithm %q, must be md5, sha256 or sha512", parts[0])
+	}
+
+	sum, err := hex.DecodeString(parts[1])
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("checksum %q isn't valid hex", parts[1])
+	}
+
+	if len(sum) != newHash().Size() {
+		return nil, nil, fmt.Errorf("%s checksum must be %d hex digits", parts[0], newHash().Size()*2)
+	}
+
+	return newHash, sum, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"context"
+	"errors"
+	"net"
+	"os"
+
+	"github.com/AmesianX/rover/rover"
+)
+
+// exit codes, so scripts can tell why rover failed
+const (
+	exitFailure   = 1 // anything else, such as being interrupted
+	exitUsage     = 2 // invalid flags or arguments
+	exitNetwork   = 3 // unable to reach the server or download from it
+	exitZip       = 4 // the zip is invalid or corrupt
+	exitNotFound  = 5 // the files asked for aren't in the zip
+	exitLocalIO   = 6 // unable to read or write a local file
+	exitEncrypted = 7 // the file is encrypted, which isn't supported
+)
+
+// the exit codes as listed at the end of -h
+const exitCodes = `
+Exit codes:
+  0	success
+  1	interrupted, or any other failure
+  2	invalid flags or arguments
+  3	unable to reach the server or download from it
+  4	the zip is invalid or corrupt
+  5	the files asked for aren't in the zip
+  6	unable to read or write a local file
+  7	the file is encrypted, which isn't supported
+`
+
+// an error with a local file which doesn't already say so by being an
+// *os.PathError, e.g. refusing to overwrite it
+type localError struct {
+	error
+}
+
+func (e localError) Unwrap() error {
+	return e.error
+}
+
+// returns the exit code for err, which came from opening a zip or extracting a
+// file from it. anything unrecognised is assumed to come from the server
+func exitStatus(err error) int {
+	var corrupt flate.CorruptInputError
+	var netErr net.Error
+	var local localError
+	var pathErr *os.PathError
+	var linkErr *os.LinkError
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitFailure
+	case errors.Is(err, rover.ErrInvalidURL):
+		return exitUsage
+	case errors.Is(err, rover.ErrEncrypted):
+		return exitEncrypted
+	case errors.Is(err, rover.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, zip.ErrFormat), errors.Is(err, zip.ErrAlgorithm), errors.Is(err, zip.ErrChecksum),
+		errors.Is(err, errUnsafePath), errors.Is(err, errBadSymlink), errors.Is(err, errChecksumMismatch),
+		errors.As(err, &corrupt):
+		return exitZip
+	// a network error wraps an *os.SyscallError, so it's checked for first
+	case errors.As(err, &netErr):
+		return exitNetwork
+	case errors.As(err, &local), errors.As(err, &pathErr), errors.As(err, &linkErr):
+		return exitLocalIO
+	}
+
+	return exitNetwork
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/AmesianX/rover/rover"
+	"github.com/dustin/go-humanize"
+)
+
+// the -info of a file with -json, which is its -json listing entry with the
+// details only -info shows
+type jsonInfo struct {
+	jsonEntry
+	Mode          string `json:"mode"`
+	ExternalAttrs uint32 `json:"external_attrs"`
+	Offset        int64  `json:"offset"` // of its contents within the zip
+}
+
+// prints the details of file to stdout, as JSON with -json, without
+// downloading its contents
+func printInfo(archive *rover.Archive, file rover.ZipEntry) error {
+	offset, err := archive.DataOffset(file.Name)
+
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return json.NewEncoder(os.Stdout).Encode(jsonInfo{
+			jsonEntry:     newJSONEntry(file),
+			Mode:          file.Mode.String(),
+			ExternalAttrs: file.ExternalAttrs,
+			Offset:        offset,
+		})
+	}
+
+	modified := "unknown"
+
+	if !file.Modified.IsZero() {
+		modified = file.Modified.Format(time.RFC3339)
+	}
+
+	rows := [][2]string{
+		{"Name", displayName(file)},
+		{"Size", fmt.Sprintf("%s (%d bytes)", humanize.Bytes(file.UncompressedSize), file.UncompressedSize)},
+		{"Compressed", fmt.Sprintf("%s (%d bytes)", humanize.Bytes(file.CompressedSize), file.CompressedSize)},
+		{"CRC32", fmt.Sprintf("%08x", file.CRC32)},
+		{"Method", fmt.Sprintf("%s (%d)", methodName(file.Method), file.Method)},
+		{"Modified", modified},
+		{"Mode", file.Mode.String()},
+		{"Attributes", fmt.Sprintf("0x%08x", file.ExternalAttrs)},
+		{"Offset", fmt.Sprintf("%d", offset)},
+	}
+
+	for _, row := range rows {
+		if _, err := fmt.Printf("%-12s%s\n", row[0]+":", row[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
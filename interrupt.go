@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// temporary files being downloaded to, which are removed if rover is
+// interrupted
+var tempFiles = struct {
+	sync.Mutex
+	names map[string]bool
+}{names: make(map[string]bool)}
+
+func addTempFile(name string) {
+	tempFiles.Lock()
+	defer tempFiles.Unlock()
+
+	tempFiles.names[name] = true
+}
+
+func removeTempFile(name string) {
+	tempFiles.Lock()
+	defer tempFiles.Unlock()
+
+	delete(tempFiles.names, name)
+}
+
+// removes every temporary file there is
+func removeTempFiles() {
+	tempFiles.Lock()
+	defer tempFiles.Unlock()
+
+	for name := range tempFiles.names {
+		os.Remove(name)
+		delete(tempFiles.names, name)
+	}
+}
+
+// exits with code after removing any temporary files, which os.Exit would
+// leave behind
+func exit(code int) {
+	removeTempFiles()
+	os.Exit(code)
+}
+
+// how long downloads have to stop after ctrl-c or SIGTERM before rover exits
+// regardless
+const stopTimeout = 5 * time.Second
+
+// returns a context which is cancelled on ctrl-c or SIGTERM, so downloads stop
+// and clean up after themselves. if rover hasn't exited within stopTimeout, or
+// on a second signal, any temporary files are removed and it exits anyway
+func handleInterrupts() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+
+		fmt.Fprintf(os.Stderr, "\n%v, stopping\n", sig)
+		cancel()
+
+		select {
+		case <-signals:
+		case <-time.After(stopTimeout):
+		}
+
+		exit(exitFailure)
+	}()
+
+	return ctx
+}
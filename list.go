@@ -0,0 +1,476 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AmesianX/rover/rover"
+	"github.com/dustin/go-humanize"
+)
+
+// how -l lists files
+type listFormat int
+
+const (
+	listShort   listFormat = iota // sizes and names
+	listLong                      // sizes, compression, methods, dates, CRC32s and names
+	listJSON                      // a JSON array of entries
+	listCSV                       // a header row then a row per entry
+	listTree                      // a tree of directories
+	listSummary                   // just how many files there are and their sizes
+	listNames                     // just names, each followed by a NUL
+)
+
+// prints files to stdout in format, followed by the archive's comment with
+// -comment
+func listFiles(files []rover.ZipEntry, format listFormat, comment string) error {
+	if files == nil {
+		return errors.New("file read error")
+	}
+
+	var err error
+
+	switch format {
+	case listLong:
+		err = listFilesLong(files)
+	case listJSON:
+		return listFilesJSON(files, comment)
+	case listCSV:
+		return listFilesCSV(files)
+	case listTree:
+		err = listFilesTree(files, treeDepth)
+	case listSummary:
+		_, err = fmt.Println(summary(files))
+	case listNames:
+		return listNamesNul(files)
+	default:
+		err = listFilesShort(files)
+	}
+
+	if err != nil || !showComment || comment == "" {
+		return err
+	}
+
+	// the comment is printed as it is, other than ending it with a newline
+	if _, err := fmt.Print(comment); err != nil || strings.HasSuffix(comment, "\n") {
+		return err
+	}
+
+	_, err = fmt.Println()
+
+	return err
+}
+
+// prints the names of files, each followed by a NUL rather than a newline so
+// any name can be read back safely, e.g. by xargs -0 or -files-from
+func listNamesNul(files []rover.ZipEntry) error {
+	w := bufio.NewWriter(os.Stdout)
+
+	for _, f := range files {
+		w.WriteString(f.Name)
+		w.WriteByte(0)
+	}
+
+	return w.Flush()
+}
+
+// prints the sizes and names of files, followed by a summary
+func listFilesShort(files []rover.ZipEntry) error {
+	for _, f := range files {
+		fmt.Printf("%6s \t %s\n", humanize.Bytes(f.UncompressedSize), displayName(f))
+	}
+
+	fmt.Println("------")
+	_, err := fmt.Println(summary(files))
+
+	return err
+}
+
+// returns only the files (entryType "f") or the directories ("d") in entries,
+// or all of them if entryType is "". the directories include those which are
+// only implied by the paths of the files in them, in the order they're first
+// seen
+func filterType(entries []rover.ZipEntry, entryType string) []rover.ZipEntry {
+	if entryType == "" {
+		return entries
+	}
+
+	filtered := make([]rover.ZipEntry, 0, len(entries))
+	seen := make(map[string]bool)
+
+	for _, f := range entries {
+		if entryType == "f" {
+			if !f.IsDir() {
+				filtered = append(filtered, f)
+			}
+
+			continue
+		}
+
+		// the parents of a directory come before it, like in a zip
+		for i, c := range f.Name {
+			if c != '/' || i == len(f.Name)-1 {
+				continue
+			}
+
+			if dir := f.Name[:i+1]; !seen[dir] {
+				seen[dir] = true
+				filtered = append(filtered, rover.ZipEntry{Name: dir})
+			}
+		}
+
+		if f.IsDir() && !seen[f.Name] {
+			seen[f.Name] = true
+			filtered = append(filtered, f)
+		}
+	}
+
+	return filtered
+}
+
+// returns the name of f to list, marked if it's encrypted as it can't be
+// downloaded
+func displayName(f rover.ZipEntry) string {
+	if f.Encrypted {
+		return f.Name + " (encrypted)"
+	}
+
+	return f.Name
+}
+
+// returns how many files and directories there are, their total size and
+// how much compression saved, e.g. "3 files, 1 directory, 5.5 kB (52 B
+// compressed, 99.0% saved)"
+func summary(entries []rover.ZipEntry) string {
+	var files, dirs int
+	var total, compressed uint64
+
+	for _, f := range entries {
+		if f.IsDir() {
+			dirs++
+			continue
+		}
+
+		files++
+		total += f.UncompressedSize
+		compressed += f.CompressedSize
+	}
+
+	return fmt.Sprintf("%s, %s, %s (%s compressed, %.1f%% saved)",
+		plural(files, "file", "files"),
+		plural(dirs, "directory", "directories"),
+		humanize.Bytes(total),
+		humanize.Bytes(compressed),
+		saved(compressed, total),
+	)
+}
+
+// returns n followed by singular or plural, to suit n
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+
+	return fmt.Sprintf("%d %s", n, plural)
+}
+
+// sorts files by name, size, time (or date) or compressed (size), keeping the
+// order in the zip for files which compare equal. by may be "" to keep the
+// order in the zip, which is still reversed if reverse is true
+func sortFiles(files []rover.ZipEntry, by string, reverse bool) {
+	var less func(a, b rover.ZipEntry) bool
+
+	switch by {
+	case "name":
+		less = func(a, b rover.ZipEntry) bool { return a.Name < b.Name }
+	case "size":
+		less = func(a, b rover.ZipEntry) bool { return a.UncompressedSize < b.UncompressedSize }
+	case "time", "date":
+		less = func(a, b rover.ZipEntry) bool { return a.Modified.Before(b.Modified) }
+	case "compressed":
+		less = func(a, b rover.ZipEntry) bool { return a.CompressedSize < b.CompressedSize }
+	default:
+		if reverse {
+			for i, j := 0, len(files)-1; i < j; i, j = i+1, j-1 {
+				files[i], files[j] = files[j], files[i]
+			}
+		}
+
+		return
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if reverse {
+			return less(files[j], files[i])
+		}
+
+		return less(files[i], files[j])
+	})
+}
+
+// an entry in the -json listing
+type jsonEntry struct {
+	Name             string `json:"name"`
+	CompressedSize   uint64 `json:"compressed_size"`
+	UncompressedSize uint64 `json:"uncompressed_size"`
+	Modified         string `json:"modified"`
+	CRC32            string `json:"crc32"` // in hex, as unzip -v shows it
+	Method           string `json:"method"`
+	MethodID         uint16 `json:"method_id"` // as stored in the zip, e.g. 8 for deflate
+	IsDir            bool   `json:"is_dir"`
+	Encrypted        bool   `json:"encrypted"`
+}
+
+// prints files to stdout as a JSON array, one element at a time so the
+// whole listing needn't be held in memory twice. with -comment, the array is
+// the "files" of an object which has the archive's "comment" too
+func listFilesJSON(files []rover.ZipEntry, comment string) error {
+	encoder := json.NewEncoder(os.Stdout)
+
+	if showComment {
+		encodedComment, err := json.Marshal(comment)
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Printf(`{"comment":%s,"files":`, encodedComment); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Print("["); err != nil {
+		return err
+	}
+
+	for i, f := range files {
+		if i > 0 {
+			if _, err := fmt.Print(","); err != nil {
+				return err
+			}
+		}
+
+		if err := encoder.Encode(newJSONEntry(f)); err != nil {
+			return err
+		}
+	}
+
+	if showComment {
+		_, err := fmt.Println("]}")
+		return err
+	}
+
+	_, err := fmt.Println("]")
+
+	return err
+}
+
+// returns the JSON for f
+func newJSONEntry(f rover.ZipEntry) jsonEntry {
+	return jsonEntry{
+		Name:             f.Name,
+		CompressedSize:   f.CompressedSize,
+		UncompressedSize: f.UncompressedSize,
+		Modified:         formatTime(f.Modified),
+		CRC32:            fmt.Sprintf("%08x", f.CRC32),
+		Method:           methodName(f.Method),
+		MethodID:         f.Method,
+		IsDir:            f.IsDir(),
+		Encrypted:        f.Encrypted,
+	}
+}
+
+// prints files to stdout as CSV, with their sizes in bytes
+func listFilesCSV(files []rover.ZipEntry) error {
+	w := csv.NewWriter(os.Stdout)
+
+	w.Write([]string{"name", "size", "compressed", "crc32", "modified", "method"})
+
+	for _, f := range files {
+		w.Write([]string{
+			f.Name,
+			strconv.FormatUint(f.UncompressedSize, 10),
+			strconv.FormatUint(f.CompressedSize, 10),
+			fmt.Sprintf("%08x", f.CRC32),
+			formatTime(f.Modified),
+			methodName(f.Method),
+		})
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
+
+// returns the name of a zip compression method
+func methodName(method uint16) string {
+	switch method {
+	case zip.Store:
+		return "store"
+	case zip.Deflate:
+		return "deflate"
+	}
+
+	return fmt.Sprintf("method %d", method)
+}
+
+// prints files in columns sized to fit the largest values, followed by the
+// totals, like unzip -v
+func listFilesLong(files []rover.ZipEntry) error {
+	var total, totalCompressed uint64
+	var count int
+
+	rows := [][]string{{"Size", "Compressed", "Saved", "Method", "Modified", "CRC32", "Name"}}
+
+	for _, f := range files {
+		total += f.UncompressedSize
+		totalCompressed += f.CompressedSize
+
+		if !f.IsDir() {
+			count++
+		}
+
+		rows = append(rows, []string{
+			humanize.Bytes(f.UncompressedSize),
+			humanize.Bytes(f.CompressedSize),
+			fmt.Sprintf("%.1f%%", saved(f.CompressedSize, f.UncompressedSize)),
+			methodName(f.Method),
+			formatModified(f.Modified),
+			fmt.Sprintf("%08x", f.CRC32),
+			displayName(f),
+		})
+	}
+
+	rows = append(rows, []string{
+		humanize.Bytes(total),
+		humanize.Bytes(totalCompressed),
+		fmt.Sprintf("%.1f%%", saved(totalCompressed, total)),
+		"",
+		"",
+		"",
+		plural(count, "file", "files"),
+	})
+
+	widths := make([]int, len(rows[0]))
+
+	for _, row := range rows {
+		for i, cell := range row {
+			// fmt pads to a number of runes, not bytes
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	for i, row := range rows {
+		// the totals are set apart from the files, like the header
+		if i == len(rows)-1 {
+			printRow(separators(widths), widths)
+		}
+
+		if err := printRow(row, widths); err != nil {
+			return err
+		}
+
+		if i == 0 {
+			printRow(separators(widths), widths)
+		}
+	}
+
+	return nil
+}
+
+// returns when a file was modified for the long listing, or "" if that's not
+// known (e.g. for directories only implied by the paths of files)
+func formatModified(modified time.Time) string {
+	if modified.IsZero() {
+		return ""
+	}
+
+	return modified.Format("2006-01-02 15:04")
+}
+
+// returns when a file was modified for JSON and CSV, or "" if that's not known
+func formatTime(modified time.Time) string {
+	if modified.IsZero() {
+		return ""
+	}
+
+	return modified.Format(time.RFC3339)
+}
+
+// prints a row of a long listing, with the sizes right aligned
+func printRow(row []string, widths []int) error {
+	_, err := fmt.Printf("%*s  %*s  %*s  %-*s  %-*s  %-*s  %s\n",
+		widths[0], row[0],
+		widths[1], row[1],
+		widths[2], row[2],
+		widths[3], row[3],
+		widths[4], row[4],
+		widths[5], row[5],
+		row[6],
+	)
+
+	return err
+}
+
+// returns a row of dashes as wide as each column
+func separators(widths []int) []string {
+	row := make([]string, len(widths))
+
+	for i, width := range widths {
+		row[i] = strings.Repeat("-", width)
+	}
+
+	return row
+}
+
+// returns the percentage of size saved by compressing it to compressed, to
+// one decimal place. it's rounded down so nearly all isn't shown as 100%
+func saved(compressed, size uint64) float64 {
+	if size == 0 || compressed >= size {
+		return 0
+	}
+
+	return float64((size-compressed)*1000/size) / 10
+}
+
+// the -json error for files which weren't in the zip
+type jsonError struct {
+	Error   string        `json:"error"`
+	Missing []jsonMissing `json:"missing"`
+}
+
+// a file which wasn't in the zip, and names it might have been meant to be
+type jsonMissing struct {
+	Name        string   `json:"name"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// prints a JSON object naming the files which weren't in the zip to stdout,
+// or to stderr if a file is being written to stdout
+func reportMissingJSON(missing []string, entries []rover.ZipEntry) error {
+	report := jsonError{Error: "not found", Missing: make([]jsonMissing, 0, len(missing))}
+
+	for _, name := range missing {
+		report.Missing = append(report.Missing, jsonMissing{Name: name, Suggestions: suggest(name, entries)})
+	}
+
+	output := os.Stdout
+
+	if localFile == "-" {
+		output = os.Stderr
+	}
+
+	return json.NewEncoder(output).Encode(report)
+}
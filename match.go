@@ -0,0 +1,82 @@
+package main
+
+import (
+	"path"
+	"strings"
+
+	"github.com/AmesianX/rover/rover"
+)
+
+// reports whether name refers to a directory in the zip
+func isDirectory(name string) bool {
+	return strings.HasSuffix(name, "/")
+}
+
+// reports whether name matches any of the -exclude patterns. a pattern
+// without a slash matches the base name at any depth (e.g. .DS_Store or
+// *.map), and one ending in a slash matches everything in that directory
+// (e.g. __MACOSX/). any other pattern is matched against the full name, and
+// matching a directory matches everything in it too (so __MACOSX/* skips
+// __MACOSX/a/b as well as __MACOSX/a)
+func isExcluded(name string) bool {
+	if ignoreCase {
+		name = strings.ToLower(name)
+	}
+
+	for _, exclude := range excludes {
+		if ignoreCase {
+			exclude = strings.ToLower(exclude)
+		}
+
+		var matched bool
+
+		switch {
+		case isDirectory(exclude):
+			matched = strings.HasPrefix(name, exclude)
+		case !strings.Contains(exclude, "/"):
+			matched, _ = path.Match(exclude, path.Base(name))
+		default:
+			matched = matchDirectories(exclude, name)
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// returns the files which aren't excluded by -exclude
+func excludeFiles(files []rover.ZipEntry) []rover.ZipEntry {
+	if len(excludes) == 0 {
+		return files
+	}
+
+	kept := make([]rover.ZipEntry, 0, len(files))
+
+	for _, f := range files {
+		if !isExcluded(f.Name) {
+			kept = append(kept, f)
+		}
+	}
+
+	return kept
+}
+
+// reports whether pattern matches name, or any of the directories it's in
+func matchDirectories(pattern, name string) bool {
+	for {
+		if matched, _ := rover.Match(pattern, strings.TrimSuffix(name, "/")); matched {
+			return true
+		}
+
+		i := strings.LastIndex(strings.TrimSuffix(name, "/"), "/")
+
+		if i < 0 {
+			return false
+		}
+
+		name = name[:i]
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// reports whether outputFile may be written to. an existing file is only
+// overwritten with -f, or if the user says so when asked on the terminal; -n,
+// or not being able to ask, keeps it
+func mayOverwrite(outputFile string) (bool, error) {
+	if outputFile == "-" {
+		return true, nil
+	}
+
+	info, err := os.Stat(outputFile)
+
+	if os.IsNotExist(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if info.IsDir() {
+		return false, localError{fmt.Errorf("%s is a directory", outputFile)}
+	}
+
+	// resuming adds to the file rather than replacing it
+	if resume || force {
+		return true, nil
+	}
+
+	if noClobber || !interactive() {
+		return false, localError{fmt.Errorf("%s already exists (use -f/-force to overwrite it)", outputFile)}
+	}
+
+	for {
+		fmt.Fprintf(os.Stderr, "Replace %s? [y/N]: ", outputFile)
+
+		line, err := stdin.ReadString('\n')
+
+		if err != nil {
+			return false, err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			return true, nil
+		case "", "n", "no":
+			return false, nil
+		}
+	}
+}
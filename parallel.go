@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/AmesianX/rover/rover"
+)
+
+// downloads files using -j workers at once, each with its own copy of archive
+// as it can't be shared, until ctx is done. returns the number of files and
+// bytes extracted, and the exit code of the first to fail, or 0 if none did
+func extractParallel(ctx context.Context, archive *rover.Archive, files []download) (uint64, uint64, int) {
+	n := workers
+
+	if n > len(files) {
+		n = len(files)
+	}
+
+	archives := []*rover.Archive{archive}
+
+	for len(archives) < n {
+		a, err := archive.Reopen()
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to open %s: %v\n", sourceURL, err)
+			return 0, 0, exitStatus(err)
+		}
+
+		archives = append(archives, a)
+	}
+
+	overall = &overallProgress{files: len(files)}
+	defer func() { overall = nil }()
+
+	var mu sync.Mutex
+	var extracted, extractedBytes uint64
+
+	status := 0
+
+	queue := make(chan download)
+
+	var wg sync.WaitGroup
+
+	for _, a := range archives {
+		wg.Add(1)
+
+		go func(a *rover.Archive) {
+			defer wg.Done()
+
+			for d := range queue {
+				written, err := extractFile(a, d.file, d.outputFile)
+
+				mu.Lock()
+
+				if errors.Is(err, context.Canceled) {
+					// already reported
+				} else if err != nil {
+					fmt.Fprintf(os.Stderr, "\rUnable to extract %s from zip: %v\n", d.file.Name, err)
+
+					if status == 0 {
+						status = exitStatus(err)
+					}
+				} else {
+					extracted++
+					extractedBytes += written
+				}
+
+				mu.Unlock()
+
+				overall.finish()
+			}
+		}(a)
+	}
+
+	for _, d := range files {
+		if ctx.Err() != nil {
+			break
+		}
+
+		queue <- d
+	}
+
+	close(queue)
+	wg.Wait()
+
+	if verbose {
+		fmt.Fprintln(messages)
+	}
+
+	return extracted, extractedBytes, status
+}
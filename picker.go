@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/AmesianX/rover/rover"
+	"github.com/dustin/go-humanize"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// answers to questions asked on the terminal
+var stdin = bufio.NewReader(os.Stdin)
+
+// reports whether the user can be asked questions on the terminal
+func interactive() bool {
+	return !noInteractive && terminal.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// prints a numbered menu of files on stderr and asks which of them to
+// download, by number or a for all of them
+func pickFiles(files []rover.ZipEntry) ([]rover.ZipEntry, error) {
+	for i, f := range files {
+		fmt.Fprintf(os.Stderr, "%3d) %s  %s  %s\n", i+1, f.Name, humanize.Bytes(f.UncompressedSize), formatModified(f.Modified))
+	}
+
+	for {
+		fmt.Fprintf(os.Stderr, "Download which file? [1-%d, a for all]: ", len(files))
+
+		line, err := stdin.ReadString('\n')
+
+		if err != nil {
+			return nil, err
+		}
+
+		choice := strings.TrimSpace(line)
+
+		if choice == "a" {
+			return files, nil
+		}
+
+		if n, err := strconv.Atoi(choice); err == nil && n >= 1 && n <= len(files) {
+			return files[n-1 : n], nil
+		}
+
+		fmt.Fprintf(os.Stderr, "%q isn't one of the choices\n", choice)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// returns a progress bar fitting the terminal width given a progress
+// percentage, along with the current download speed (in bytes per second)
+// and the estimated time remaining. a speed of zero or a negative time
+// remaining means it isn't known yet
+func progressBar(progress int, speed float64, eta time.Duration) (progressBar string) {
+
+	var width int
+
+	if runtime.GOOS == "windows" {
+		// we'll just assume it's standard terminal width
+		width = 80
+	} else {
+		width, _, _ = terminal.GetSize(0)
+	}
+
+	// take off 65 for extra info (e.g. percentage and speed)
+	width = width - 65
+
+	// get the current progress
+	currentProgress := (progress * width) / 100
+
+	progressBar = "["
+
+	// fill up progress
+	for i := 0; i < currentProgress; i++ {
+		progressBar = progressBar + "="
+	}
+
+	progressBar = progressBar + ">"
+
+	// fill the rest with spaces
+	for i := width; i > currentProgress; i-- {
+		progressBar = progressBar + " "
+	}
+
+	// end the progressbar
+	progressBar = progressBar + "] " + fmt.Sprintf("%3d%%", progress)
+
+	if speed > 0 {
+		progressBar = progressBar + fmt.Sprintf(" %10s/s", humanize.Bytes(uint64(speed)))
+	} else {
+		progressBar = progressBar + fmt.Sprintf(" %12s", "--")
+	}
+
+	if eta >= 0 {
+		progressBar = progressBar + fmt.Sprintf("  ETA %-8s", formatDuration(eta))
+	} else {
+		progressBar = progressBar + fmt.Sprintf("  ETA %-8s", "--")
+	}
+
+	return progressBar
+}
+
+// returns how far along downloaded is towards total, as a percentage.
+// an empty file is considered complete
+func percentage(downloaded, total uint64) int {
+	if total == 0 {
+		return 100
+	}
+
+	return int(downloaded * 100 / total)
+}
+
+// the period the download speed is averaged over
+const speedWindow = 5 * time.Second
+
+// how often the download speed is sampled
+const speedInterval = 100 * time.Millisecond
+
+// how long a download has to run before the speed is steady enough to
+// estimate the time remaining from
+const etaDelay = time.Second
+
+// the amount downloaded at a point in time
+type speedSample struct {
+	at         time.Time
+	downloaded uint64
+}
+
+// measures the download speed over the last speedWindow, keeping samples in
+// a circular buffer large enough to span the window
+type speedometer struct {
+	samples [speedWindow/speedInterval + 1]speedSample
+	next    int       // where the next sample goes
+	count   int       // how many samples have been taken, up to len(samples)
+	start   time.Time // when the first sample was taken
+}
+
+// records that downloaded bytes have been downloaded so far
+func (s *speedometer) add(downloaded uint64) {
+	now := time.Now()
+
+	if s.count > 0 && now.Sub(s.latest().at) < speedInterval {
+		return
+	}
+
+	if s.count == 0 {
+		s.start = now
+	}
+
+	s.samples[s.next] = speedSample{at: now, downloaded: downloaded}
+	s.next = (s.next + 1) % len(s.samples)
+
+	if s.count < len(s.samples) {
+		s.count++
+	}
+}
+
+func (s *speedometer) latest() speedSample {
+	return s.samples[(s.next+len(s.samples)-1)%len(s.samples)]
+}
+
+// returns the average download speed in bytes per second over the samples
+// within speedWindow, or zero if there aren't enough samples yet
+func (s *speedometer) speed() float64 {
+	if s.count < 2 {
+		return 0
+	}
+
+	latest := s.latest()
+	oldest := latest
+
+	for i := 1; i < s.count; i++ {
+		sample := s.samples[(s.next+len(s.samples)-1-i)%len(s.samples)]
+
+		if latest.at.Sub(sample.at) > speedWindow {
+			break
+		}
+
+		oldest = sample
+	}
+
+	elapsed := latest.at.Sub(oldest.at).Seconds()
+
+	if elapsed == 0 {
+		return 0
+	}
+
+	return float64(latest.downloaded-oldest.downloaded) / elapsed
+}
+
+// returns the download speed in bytes per second over the last sampling
+// interval, which reflects how the download is going right now better than
+// speed does, or zero if there aren't enough samples yet
+func (s *speedometer) current() float64 {
+	if s.count < 2 {
+		return 0
+	}
+
+	latest := s.latest()
+	previous := s.samples[(s.next+len(s.samples)-2)%len(s.samples)]
+
+	elapsed := latest.at.Sub(previous.at).Seconds()
+
+	if elapsed == 0 {
+		return 0
+	}
+
+	return float64(latest.downloaded-previous.downloaded) / elapsed
+}
+
+// returns the time it'll take to download the remaining bytes, or -1 if the
+// download hasn't been running for long enough to tell
+func (s *speedometer) eta(remaining uint64) time.Duration {
+	speed := s.speed()
+
+	if speed == 0 || s.latest().at.Sub(s.start) < etaDelay {
+		return -1
+	}
+
+	return time.Duration(float64(remaining) / speed * float64(time.Second))
+}
+
+// formats d as minutes and seconds, e.g. 0:42, with hours if need be
+func formatDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second) / time.Second)
+
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// writes to file (if it isn't nil), keeping track of how much has been
+// written so show can draw a progress bar with -v
+type progressWriter struct {
+	file       *os.File
+	downloaded uint64 // bytes written so far, including any being resumed
+	meter      speedometer
+}
+
+func (p *progressWriter) Write(buf []byte) (int, error) {
+	n, err := len(buf), error(nil)
+
+	// with -dry-run, there's nowhere to write to
+	if p.file != nil {
+		n, err = p.file.Write(buf)
+	}
+
+	if err == nil && n < len(buf) {
+		err = io.ErrShortWrite
+	}
+
+	if err != nil {
+		return n, fmt.Errorf("unable to write to %s: %w", p.file.Name(), err)
+	}
+
+	p.downloaded += uint64(n)
+
+	// several files at once share a single line instead
+	if overall != nil {
+		overall.add(uint64(n))
+	}
+
+	return n, nil
+}
+
+// draws the progress bar with -v, as the rover.ProgressFunc of a download
+func (p *progressWriter) show(downloaded, total uint64) {
+	p.meter.add(downloaded)
+
+	if overall != nil || !verbose {
+		return
+	}
+
+	fmt.Fprintf(
+		messages,
+		"\r%s %10s/%-10s",
+		progressBar(percentage(downloaded, total), p.meter.current(), p.meter.eta(total-downloaded)),
+		humanize.Bytes(downloaded),
+		humanize.Bytes(total),
+	)
+}
+
+// the progress of several files being downloaded at once, shown on a single
+// line with -v
+type overallProgress struct {
+	mu         sync.Mutex
+	done       int    // files finished, successfully or not
+	files      int    // files to download
+	downloaded uint64 // bytes written so far
+}
+
+// set while downloading files in parallel
+var overall *overallProgress
+
+// records n more bytes written
+func (o *overallProgress) add(n uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.downloaded += n
+	o.show()
+}
+
+// records a file being finished
+func (o *overallProgress) finish() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.done++
+	o.show()
+}
+
+func (o *overallProgress) show() {
+	if verbose {
+		fmt.Fprintf(messages, "\r%d/%d files, %s", o.done, o.files, humanize.Bytes(o.downloaded))
+	}
+}
@@ -0,0 +1,212 @@
+package rover
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// AmbiguousError is returned by Find when, ignoring case, a name matches more
+// than one file
+type AmbiguousError struct {
+	Name    string
+	Matches []ZipEntry
+}
+
+func (e *AmbiguousError) Error() string {
+	var names []string
+
+	for _, f := range e.Matches {
+		names = append(names, f.Name)
+	}
+
+	return fmt.Sprintf("%s is ambiguous, it matches %s", e.Name, strings.Join(names, ", "))
+}
+
+// Find returns the files matching filename. filename may also be a glob
+// pattern (see Match), in which case every matching file is returned, or a
+// directory ending in a slash, in which case every file within it is. an
+// exact name always wins over a glob. with Options.IgnoreCase set, names and
+// patterns are compared case-insensitively, and an *AmbiguousError is returned
+// if a name matches more than one file. ErrNotFound is returned if nothing
+// matches
+func (a *Archive) Find(filename string) ([]ZipEntry, error) {
+	files, err := a.find(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newZipEntries(files), nil
+}
+
+func (a *Archive) find(filename string) ([]*zip.File, error) {
+	reader := a.reader
+
+	if reader.File == nil {
+		return nil, errors.New("file read error")
+	}
+
+	var matches []*zip.File
+
+	// a directory selects every file within it
+	if isDirectory(filename) {
+		for _, f := range reader.File {
+			if a.hasPrefix(f.Name, filename) && !isDirectory(f.Name) {
+				matches = append(matches, f)
+			}
+		}
+
+		if len(matches) == 0 {
+			return nil, ErrNotFound
+		}
+
+		return matches, nil
+	}
+
+	for _, f := range reader.File {
+		if f.Name == filename {
+			return []*zip.File{f}, nil
+		}
+
+		if a.opts.IgnoreCase && strings.EqualFold(f.Name, filename) {
+			matches = append(matches, f)
+		}
+	}
+
+	if len(matches) > 1 {
+		return nil, &AmbiguousError{Name: filename, Matches: newZipEntries(matches)}
+	}
+
+	if len(matches) == 1 {
+		return matches, nil
+	}
+
+	if !isPattern(filename) {
+		return nil, ErrNotFound
+	}
+
+	pattern := filename
+
+	if a.opts.IgnoreCase {
+		pattern = strings.ToLower(pattern)
+	}
+
+	for _, f := range reader.File {
+		// directories can't be downloaded
+		if isDirectory(f.Name) {
+			continue
+		}
+
+		name := f.Name
+
+		if a.opts.IgnoreCase {
+			name = strings.ToLower(name)
+		}
+
+		matched, err := Match(pattern, name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if matched {
+			matches = append(matches, f)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return matches, nil
+}
+
+// FindRegexp returns the files whose names match re, or ErrNotFound if none do
+func (a *Archive) FindRegexp(re *regexp.Regexp) ([]ZipEntry, error) {
+	if a.reader.File == nil {
+		return nil, errors.New("file read error")
+	}
+
+	var matches []*zip.File
+
+	for _, f := range a.reader.File {
+		if re.MatchString(f.Name) && !isDirectory(f.Name) {
+			matches = append(matches, f)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return newZipEntries(matches), nil
+}
+
+func newZipEntries(files []*zip.File) []ZipEntry {
+	entries := make([]ZipEntry, 0, len(files))
+
+	for _, f := range files {
+		entries = append(entries, newZipEntry(f))
+	}
+
+	return entries
+}
+
+// reports whether name refers to a directory in the zip
+func isDirectory(name string) bool {
+	return strings.HasSuffix(name, "/")
+}
+
+// reports whether name lies within the directory prefix, honouring IgnoreCase
+func (a *Archive) hasPrefix(name, prefix string) bool {
+	if a.opts.IgnoreCase {
+		return len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix)
+	}
+
+	return strings.HasPrefix(name, prefix)
+}
+
+// reports whether pattern contains any glob metacharacters
+func isPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// Match reports whether the zip entry name matches pattern. patterns use
+// path.Match syntax for each path segment, with the addition of a "**"
+// segment which matches any number of directories (including none)
+func Match(pattern, name string) (bool, error) {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// try to match the rest of the pattern at every depth
+			for i := 0; i <= len(name); i++ {
+				if matched, err := matchSegments(pattern[1:], name[i:]); matched || err != nil {
+					return matched, err
+				}
+			}
+
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+
+		matched, err := path.Match(pattern[0], name[0])
+
+		if err != nil || !matched {
+			return false, err
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0, nil
+}
@@ -0,0 +1,138 @@
+package rover
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.txt", "a.txt", true},
+		{"*.txt", "docs/a.txt", false},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"[ab].txt", "b.txt", true},
+		{"[ab].txt", "c.txt", false},
+		{"[^ab].txt", "c.txt", true},
+		{"subdir/*.json", "subdir/x.json", true},
+		{"subdir/*.json", "subdir/deeper/x.json", false},
+		{"subdir/*.json", "other/x.json", false},
+		{"**/*.json", "x.json", true},
+		{"**/*.json", "a/b/c/x.json", true},
+		{"a/**/z.txt", "a/z.txt", true},
+		{"a/**/z.txt", "a/b/c/z.txt", true},
+		{"a/**/z.txt", "b/z.txt", false},
+		{"a/**", "a/b/c", true},
+	}
+
+	for _, test := range tests {
+		got, err := Match(test.pattern, test.name)
+
+		if err != nil {
+			t.Errorf("Match(%q, %q) returned %v", test.pattern, test.name, err)
+		} else if got != test.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+func TestMatchBadPattern(t *testing.T) {
+	if _, err := Match("[", "a"); err == nil {
+		t.Error("Match with an unclosed [ returned no error")
+	}
+}
+
+// returns the names of files
+func entryNames(files []ZipEntry) []string {
+	var names []string
+
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+
+	return names
+}
+
+func TestFind(t *testing.T) {
+	archive := newTestArchive(t, Options{},
+		testFile{name: "*.txt", content: "literal"},
+		testFile{name: "a.txt", content: "a"},
+		testFile{name: "b.txt", content: "b"},
+		testFile{name: "docs/", content: ""},
+		testFile{name: "docs/c.txt", content: "c"},
+		testFile{name: "docs/api/d.txt", content: "d"},
+		testFile{name: "docsx/e.txt", content: "e"},
+	)
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"a.txt", []string{"a.txt"}},
+		// an exact name wins over the glob it also is
+		{"*.txt", []string{"*.txt"}},
+		{"?.txt", []string{"*.txt", "a.txt", "b.txt"}},
+		{"docs/*.txt", []string{"docs/c.txt"}},
+		{"**/*.txt", []string{"*.txt", "a.txt", "b.txt", "docs/c.txt", "docs/api/d.txt", "docsx/e.txt"}},
+		// a directory is everything within it, but not the directory itself
+		// or its neighbours with the same prefix
+		{"docs/", []string{"docs/c.txt", "docs/api/d.txt"}},
+	}
+
+	for _, test := range tests {
+		files, err := archive.Find(test.name)
+
+		if err != nil {
+			t.Errorf("Find(%q) returned %v", test.name, err)
+		} else if got := entryNames(files); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Find(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	for _, name := range []string{"A.txt", "c.txt", "nope/", "*.json"} {
+		if _, err := archive.Find(name); err != ErrNotFound {
+			t.Errorf("Find(%q) returned %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func TestFindIgnoreCase(t *testing.T) {
+	archive := newTestArchive(t, Options{IgnoreCase: true},
+		testFile{name: "README.TXT", content: "upper"},
+		testFile{name: "readme.txt", content: "lower"},
+		testFile{name: "Docs/A.txt", content: "a"},
+		testFile{name: "config.yaml", content: "c"},
+	)
+
+	// an exact name still wins
+	if files, err := archive.Find("readme.txt"); err != nil || !reflect.DeepEqual(entryNames(files), []string{"readme.txt"}) {
+		t.Errorf("Find(%q) = %q, %v", "readme.txt", entryNames(files), err)
+	}
+
+	if files, err := archive.Find("CONFIG.YAML"); err != nil || !reflect.DeepEqual(entryNames(files), []string{"config.yaml"}) {
+		t.Errorf("Find(%q) = %q, %v", "CONFIG.YAML", entryNames(files), err)
+	}
+
+	_, err := archive.Find("Readme.txt")
+
+	var ambiguous *AmbiguousError
+
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("Find(%q) returned %v, want an *AmbiguousError", "Readme.txt", err)
+	}
+
+	if got, want := entryNames(ambiguous.Matches), []string{"README.TXT", "readme.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ambiguous matches are %q, want %q", got, want)
+	}
+
+	for _, name := range []string{"docs/", "*.TXT", "docs/*.TXT"} {
+		if _, err := archive.Find(name); err != nil {
+			t.Errorf("Find(%q) returned %v", name, err)
+		}
+	}
+}
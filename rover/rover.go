@@ -0,0 +1,551 @@
+// Package rover downloads individual files from a zip archive on an HTTP
+// server, fetching only the parts of the archive it needs with range requests
+// rather than the whole thing.
+package rover
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/DHowett/ranger"
+)
+
+const defaultBufferSize = 128 * 1024
+
+// ProgressFunc is called as a file is extracted, with the number of bytes
+// written so far (including any skipped when resuming) and the number expected
+// in all
+type ProgressFunc func(downloaded, total uint64)
+
+// ErrNotFound is returned when no file in the archive has the name asked for
+var ErrNotFound = errors.New("unable to find file")
+
+// ErrEncrypted is returned when extracting an encrypted file, as archive/zip
+// can't decrypt them
+var ErrEncrypted = errors.New("file is encrypted, which isn't supported")
+
+// ErrInvalidURL is returned when the URL given can't be downloaded from, as it
+// doesn't parse or isn't http or https
+var ErrInvalidURL = errors.New("invalid URL")
+
+// ErrNoRanges is returned by Open when the server ignores range requests,
+// sending the whole zip instead of the part asked for
+var ErrNoRanges = errors.New("the server doesn't support range requests, so files can't be extracted without downloading the whole zip")
+
+// Options configures how an archive is fetched and extracted. the zero value
+// is ready to use
+type Options struct {
+	// Timeout limits each HTTP request, zero means no limit
+	Timeout time.Duration
+
+	// Transport makes the HTTP requests, http.DefaultTransport if nil
+	Transport http.RoundTripper
+
+	// IgnoreCase makes Find compare names case-insensitively
+	IgnoreCase bool
+
+	// Limit stops extracting a file after this many bytes, zero means no limit
+	Limit uint64
+
+	// SkipVerify turns off checking each extracted file against its CRC32
+	SkipVerify bool
+
+	// Retries is the number of times an extraction interrupted by a
+	// transient failure is resumed before giving up
+	Retries int
+
+	// RetryDelay is the delay before the first retry, doubled after each
+	// attempt
+	RetryDelay time.Duration
+
+	// OnRetry, if set, is called before each retry with the error that
+	// caused it and the delay before it's made
+	OnRetry func(err error, delay time.Duration)
+}
+
+// ZipEntry describes a file (or directory, if its name ends in a slash) in
+// the archive
+type ZipEntry struct {
+	Name             string
+	CompressedSize   uint64
+	UncompressedSize uint64
+	Modified         time.Time // zero if the zip doesn't record it
+	CRC32            uint32
+	Method           uint16      // compression method, usually zip.Store or zip.Deflate
+	Mode             os.FileMode // permissions and type, as far as the zip records them
+	ExternalAttrs    uint32      // the raw attributes Mode is taken from, which depend on the OS that made the zip
+	Encrypted        bool        // whether it's encrypted, so can't be extracted
+}
+
+// IsDir reports whether the entry is a directory
+func (e ZipEntry) IsDir() bool {
+	return isDirectory(e.Name)
+}
+
+// Archive is a zip archive on an HTTP server. it isn't safe for concurrent
+// use, so use Reopen to get another for each goroutine
+type Archive struct {
+	ctx    context.Context
+	url    string
+	opts   Options
+	reader *zip.Reader
+	files  map[string]*zip.File
+	size   int64
+	local  *os.File // the whole zip, if it was opened with OpenWhole
+}
+
+// Open reads the central directory of the zip archive at rawURL, which must
+// be an http or https URL
+func Open(rawURL string, opts Options) (*Archive, error) {
+	return OpenContext(context.Background(), rawURL, opts)
+}
+
+// OpenContext is like Open, but the archive stops reading from the server
+// once ctx is done, so cancelling ctx makes an extraction in progress return
+// ctx.Err() promptly
+func OpenContext(ctx context.Context, rawURL string, opts Options) (*Archive, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	downloadURL, err := parseURL(rawURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: opts.Transport,
+	}
+
+	if err := checkRanges(ctx, client, downloadURL); err != nil {
+		return nil, err
+	}
+
+	reader, err := ranger.NewReader(
+		&ranger.HTTPRanger{
+			URL:    downloadURL,
+			Client: client,
+		},
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to create reader: %w", err)
+	}
+
+	readerLen, err := reader.Length()
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to get length: %w", err)
+	}
+
+	return newArchive(ctx, rawURL, opts, reader, readerLen, nil)
+}
+
+// reads the central directory of the zip in r, which is size bytes long
+func newArchive(ctx context.Context, rawURL string, opts Options, r io.ReaderAt, size int64, local *os.File) (*Archive, error) {
+	zipReader, err := zip.NewReader(contextReaderAt{ctx, r}, size)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read zip: %w", err)
+	}
+
+	a := &Archive{
+		ctx:    ctx,
+		url:    rawURL,
+		opts:   opts,
+		reader: zipReader,
+		files:  make(map[string]*zip.File),
+		size:   size,
+		local:  local,
+	}
+
+	// the first of several files with the same name wins, like unzip
+	for _, f := range zipReader.File {
+		if _, ok := a.files[f.Name]; !ok {
+			a.files[f.Name] = f
+		}
+	}
+
+	return a, nil
+}
+
+// Reopen opens the same archive again, with its own connection to the server,
+// so files can be extracted from both at once. it shares the context of a. an
+// archive opened with OpenWhole is read from the same local copy instead
+func (a *Archive) Reopen() (*Archive, error) {
+	if a.local != nil {
+		return newArchive(a.ctx, a.url, a.opts, a.local, a.size, a.local)
+	}
+
+	return OpenContext(a.ctx, a.url, a.opts)
+}
+
+// Context returns the context the archive was opened with, which extractions
+// from it stop when done
+func (a *Archive) Context() context.Context {
+	return a.ctx
+}
+
+// Size returns the size of the whole archive in bytes
+func (a *Archive) Size() int64 {
+	return a.size
+}
+
+// Comment returns the comment stored at the end of the archive, which is
+// often "" but may run to several lines
+func (a *Archive) Comment() string {
+	return a.reader.Comment
+}
+
+// List returns every entry in the archive, in the order they're stored
+func (a *Archive) List() ([]ZipEntry, error) {
+	if a.reader.File == nil {
+		return nil, errors.New("file read error")
+	}
+
+	return newZipEntries(a.reader.File), nil
+}
+
+func newZipEntry(f *zip.File) ZipEntry {
+	return ZipEntry{
+		Name:             f.Name,
+		CompressedSize:   f.CompressedSize64,
+		UncompressedSize: f.UncompressedSize64,
+		Modified:         modified(f),
+		CRC32:            f.CRC32,
+		Method:           f.Method,
+		Mode:             f.Mode(),
+		ExternalAttrs:    f.ExternalAttrs,
+		Encrypted:        f.Flags&0x1 != 0,
+	}
+}
+
+// returns when f was modified, or the zero time if the zip doesn't say. a DOS
+// date of zero means it's unknown, though archive/zip turns it into
+// 1979-11-30 rather than the zero time
+func modified(f *zip.File) time.Time {
+	if f.ModifiedDate == 0 && f.Modified.Year() < 1980 {
+		return time.Time{}
+	}
+
+	return f.Modified
+}
+
+// DataOffset returns the offset within the archive at which the (possibly
+// compressed) contents of the file called name start. only the file's local
+// header, a few dozen bytes, is read from the server to find it
+func (a *Archive) DataOffset(name string) (int64, error) {
+	file, ok := a.files[name]
+
+	if !ok {
+		return 0, ErrNotFound
+	}
+
+	return file.DataOffset()
+}
+
+// Extract writes the contents of the file called name to w
+func (a *Archive) Extract(name string, w io.Writer) error {
+	return a.ExtractFrom(name, w, 0, nil)
+}
+
+// ExtractFrom writes the contents of the file called name to w, skipping the
+// first offset bytes. unless Options.SkipVerify is set, the CRC32 of the whole
+// file is checked once it's all been read, and an error wrapping
+// zip.ErrChecksum is returned if it doesn't match (files with a CRC32 of zero
+// in the zip aren't checked, as that means it's unknown). the skipped bytes
+// are checked as they are in the zip, so use ResumeFrom to check a partial
+// download's copy of them instead. ErrEncrypted is returned for encrypted
+// files before anything is read. progress, if it isn't nil, is called after
+// each write to w
+func (a *Archive) ExtractFrom(name string, w io.Writer, offset uint64, progress ProgressFunc) error {
+	return a.extract(name, w, nil, offset, progress)
+}
+
+// ResumeFrom finishes a partial download of the file called name, whose first
+// offset bytes are read from have, writing the rest to w. it's otherwise like
+// ExtractFrom, but the bytes read from have count towards the CRC32 rather
+// than those in the zip, so a partial download that's been corrupted fails
+// the check
+func (a *Archive) ResumeFrom(name string, w io.Writer, have io.Reader, offset uint64, progress ProgressFunc) error {
+	return a.extract(name, w, have, offset, progress)
+}
+
+// does the work of ExtractFrom and ResumeFrom. the first offset bytes of the
+// CRC32 come from have, or from the zip if it's nil
+func (a *Archive) extract(name string, w io.Writer, have io.Reader, offset uint64, progress ProgressFunc) error {
+	file, ok := a.files[name]
+
+	if !ok {
+		return ErrNotFound
+	}
+
+	// bit 0 of the general purpose flags marks encrypted files
+	if file.Flags&0x1 != 0 {
+		return ErrEncrypted
+	}
+
+	downloaded := offset
+	filesize := file.UncompressedSize64
+	buf := make([]byte, defaultBufferSize)
+
+	if a.opts.Limit != 0 {
+		filesize = a.opts.Limit
+		buf = make([]byte, getBufferSize(a.opts.Limit))
+	}
+
+	// the limit may be larger than the file
+	total := filesize
+
+	if total > file.UncompressedSize64 {
+		total = file.UncompressedSize64
+	}
+
+	// the part we already have still counts towards the CRC32
+	checksum := crc32.NewIEEE()
+
+	skipped := io.Writer(checksum)
+
+	if have != nil && offset > 0 {
+		if _, err := io.CopyN(checksum, have, int64(offset)); err != nil {
+			return fmt.Errorf("unable to read the part already downloaded: %w", err)
+		}
+
+		skipped = ioutil.Discard
+	}
+
+	// there's nothing left to download, so don't bother reading through it,
+	// though a download that's already complete can still be checked
+	if offset >= filesize {
+		if have != nil {
+			return a.checkCRC(file, checksum.Sum32(), offset)
+		}
+
+		return nil
+	}
+
+	rc, err := file.Open()
+
+	if err != nil {
+		return err
+	}
+
+	// rc is replaced if the download has to be retried
+	defer func() { rc.Close() }()
+
+	// deflated files can't be seeked, so the part we already have is read
+	// and thrown away
+	if offset > 0 {
+		if _, err := io.CopyN(skipped, rc, int64(offset)); err != nil {
+			return err
+		}
+	}
+
+	attempt := 0
+	delay := a.opts.RetryDelay
+
+	for downloaded < filesize {
+		if err := a.ctx.Err(); err != nil {
+			return err
+		}
+
+		// adjust the size of the buffer to get the exact
+		// number of bytes we want to download
+		if downloaded+uint64(len(buf)) > filesize {
+			buf = buf[:filesize-downloaded]
+		}
+
+		n, err := io.ReadFull(rc, buf)
+
+		checksum.Write(buf[:n])
+
+		if written, err := w.Write(buf[:n]); err != nil {
+			return err
+		} else if written < n {
+			return io.ErrShortWrite
+		}
+
+		downloaded += uint64(n)
+
+		if progress != nil {
+			progress(downloaded, total)
+		}
+
+		if err == nil {
+			continue
+		}
+
+		// running out of file is fine if we've got all of it, which happens
+		// when the limit is larger than the file
+		if (err == io.EOF || err == io.ErrUnexpectedEOF) && downloaded >= file.UncompressedSize64 {
+			break
+		}
+
+		// whatever went wrong, it was because we were told to stop
+		if ctxErr := a.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		if !isRetryable(err) || attempt >= a.opts.Retries {
+			return err
+		}
+
+		attempt++
+
+		if a.opts.OnRetry != nil {
+			a.opts.OnRetry(err, delay)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-a.ctx.Done():
+			return a.ctx.Err()
+		}
+
+		delay *= 2
+
+		// there's no seeking in a compressed stream, so start over
+		// and skip the bytes we already have
+		rc.Close()
+
+		if rc, err = file.Open(); err != nil {
+			return err
+		}
+
+		if _, err = io.CopyN(ioutil.Discard, rc, int64(downloaded)); err != nil {
+			return err
+		}
+	}
+
+	return a.checkCRC(file, checksum.Sum32(), downloaded)
+}
+
+// checks sum, the CRC32 of the first downloaded bytes of file, against the
+// one in the zip. a limited download only has part of the file to check, and
+// some zips written as a stream record a CRC32 of zero, leaving nothing to
+// check against
+func (a *Archive) checkCRC(file *zip.File, sum uint32, downloaded uint64) error {
+	if !a.opts.SkipVerify && downloaded == file.UncompressedSize64 && file.CRC32 != 0 && sum != file.CRC32 {
+		return fmt.Errorf("%w: expected crc32 %08x but got %08x", zip.ErrChecksum, file.CRC32, sum)
+	}
+
+	return nil
+}
+
+// CheckURL returns an error wrapping ErrInvalidURL if rawURL isn't one Open
+// can download from, without making any requests
+func CheckURL(rawURL string) error {
+	_, err := parseURL(rawURL)
+	return err
+}
+
+// parses rawURL, which must be an http or https URL
+func parseURL(rawURL string) (*url.URL, error) {
+	downloadURL, err := url.Parse(rawURL)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+
+	if downloadURL.Scheme != "http" && downloadURL.Scheme != "https" {
+		return nil, fmt.Errorf("%w: scheme must be http or https", ErrInvalidURL)
+	}
+
+	return downloadURL, nil
+}
+
+// asks the server for the first byte of the zip, returning ErrNoRanges if it
+// sends the whole thing instead. anything else wrong, like a 404, is left for
+// ranger to report
+func checkRanges(ctx context.Context, client *http.Client, downloadURL *url.URL) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL.String(), nil)
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return ErrNoRanges
+	}
+
+	return nil
+}
+
+// an io.ReaderAt which fails with ctx.Err() once ctx is done, so reading from
+// the archive stops between one range request and the next. other failures
+// are wrapped in a readError
+type contextReaderAt struct {
+	ctx context.Context
+	r   io.ReaderAt
+}
+
+func (c contextReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := c.r.ReadAt(p, off)
+
+	// archive/zip looks for io.EOF itself
+	if err != nil && err != io.EOF {
+		err = readError{err}
+	}
+
+	return n, err
+}
+
+// an error reading the archive, as opposed to decompressing what was read
+type readError struct {
+	error
+}
+
+func (e readError) Unwrap() error {
+	return e.error
+}
+
+func getBufferSize(lim uint64) uint64 {
+	if lim < defaultBufferSize {
+		return lim
+	}
+
+	return defaultBufferSize
+}
+
+// reports whether err is a transient failure reading the archive worth
+// retrying, such as the connection being dropped or timing out. running out
+// of data while decompressing isn't, as the file is corrupt and reading it
+// again won't help
+func isRetryable(err error) bool {
+	var read readError
+	var netErr net.Error
+
+	if !errors.As(err, &read) {
+		return false
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.As(err, &netErr)
+}
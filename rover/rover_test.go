@@ -0,0 +1,361 @@
+package rover
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a file in a zip made by makeZip
+type testFile struct {
+	name    string
+	content string
+}
+
+// makes a zip holding files, stored rather than compressed so their contents
+// can be found in it
+func makeZip(t *testing.T, files ...testFile) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w := zip.NewWriter(&buf)
+
+	for _, f := range files {
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Store})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// opens the zip in r, which is size bytes long, as if it were on a server
+func openZip(t *testing.T, opts Options, r io.ReaderAt, size int64) *Archive {
+	t.Helper()
+
+	archive, err := newArchive(context.Background(), "", opts, r, size, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return archive
+}
+
+// makes a zip holding files and opens it
+func newTestArchive(t *testing.T, opts Options, files ...testFile) *Archive {
+	t.Helper()
+
+	data := makeZip(t, files...)
+
+	return openZip(t, opts, bytes.NewReader(data), int64(len(data)))
+}
+
+func TestExtractEmpty(t *testing.T) {
+	archive := newTestArchive(t, Options{}, testFile{name: "empty.txt"})
+
+	var buf bytes.Buffer
+
+	err := archive.ExtractFrom("empty.txt", &buf, 0, func(downloaded, total uint64) {
+		t.Errorf("progress called with %d/%d for an empty file", downloaded, total)
+	})
+
+	if err != nil {
+		t.Fatalf("extracting an empty file returned %v", err)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("extracting an empty file wrote %d bytes", buf.Len())
+	}
+}
+
+// an io.ReaderAt which fails reads of anything from failAt on
+type failingReaderAt struct {
+	r      io.ReaderAt
+	failAt int64
+}
+
+var errReadFailed = errors.New("read failed")
+
+func (f *failingReaderAt) ReadAt(buf []byte, off int64) (int, error) {
+	if f.failAt >= 0 && off+int64(len(buf)) > f.failAt {
+		return 0, errReadFailed
+	}
+
+	return f.r.ReadAt(buf, off)
+}
+
+func TestExtractReadError(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	data := makeZip(t, testFile{name: "a.txt", content: content})
+	reader := &failingReaderAt{r: bytes.NewReader(data), failAt: -1}
+	archive := openZip(t, Options{}, reader, int64(len(data)))
+
+	offset, err := archive.DataOffset("a.txt")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// halfway through the file
+	reader.failAt = offset + int64(len(content))/2
+
+	var buf bytes.Buffer
+
+	if err := archive.Extract("a.txt", &buf); !errors.Is(err, errReadFailed) {
+		t.Errorf("extracting from a reader which fails returned %v, want %v", err, errReadFailed)
+	}
+
+	if buf.Len() >= len(content) {
+		t.Errorf("extracting from a reader which fails wrote all %d bytes", buf.Len())
+	}
+}
+
+// an io.Writer which accepts at most limit bytes, then fails
+type limitedWriter struct {
+	limit int
+	short bool // whether to write less than asked without an error instead
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (w *limitedWriter) Write(buf []byte) (int, error) {
+	if len(buf) <= w.limit {
+		w.limit -= len(buf)
+		return len(buf), nil
+	}
+
+	n := w.limit
+	w.limit = 0
+
+	if w.short {
+		return n, nil
+	}
+
+	return n, errWriteFailed
+}
+
+func TestExtractWriteError(t *testing.T) {
+	archive := newTestArchive(t, Options{}, testFile{name: "a.txt", content: strings.Repeat("x", 1000)})
+
+	if err := archive.Extract("a.txt", &limitedWriter{limit: 10}); !errors.Is(err, errWriteFailed) {
+		t.Errorf("extracting to a writer which fails returned %v, want %v", err, errWriteFailed)
+	}
+
+	if err := archive.Extract("a.txt", &limitedWriter{limit: 10, short: true}); !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("extracting to a writer which writes short returned %v, want %v", err, io.ErrShortWrite)
+	}
+}
+
+// makes a zip holding a single file, name, with one byte of its contents
+// changed after its CRC32 was worked out, and opens it with opts
+func newCorruptArchive(t *testing.T, opts Options, name, content string) *Archive {
+	t.Helper()
+
+	data := makeZip(t, testFile{name: name, content: content})
+	offset, err := openZip(t, opts, bytes.NewReader(data), int64(len(data))).DataOffset(name)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data[offset+int64(len(content))/2] ^= 0xff
+
+	return openZip(t, opts, bytes.NewReader(data), int64(len(data)))
+}
+
+func TestExtractChecksum(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+
+	archive := newCorruptArchive(t, Options{}, "a.txt", content)
+
+	if err := archive.Extract("a.txt", ioutil.Discard); !errors.Is(err, zip.ErrChecksum) {
+		t.Errorf("extracting a corrupt file returned %v, want %v", err, zip.ErrChecksum)
+	}
+
+	// only part of the file has nothing to check
+	limited := newCorruptArchive(t, Options{Limit: 100}, "a.txt", content)
+
+	if err := limited.Extract("a.txt", ioutil.Discard); err != nil {
+		t.Errorf("extracting part of a corrupt file returned %v", err)
+	}
+}
+
+func TestResumeChecksum(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	archive := newTestArchive(t, Options{}, testFile{name: "a.txt", content: content})
+
+	var rest bytes.Buffer
+
+	if err := archive.ResumeFrom("a.txt", &rest, strings.NewReader(content[:400]), 400, nil); err != nil {
+		t.Errorf("resuming from an intact part returned %v", err)
+	}
+
+	if rest.String() != content[400:] {
+		t.Errorf("resuming wrote %d bytes, want the last %d", rest.Len(), len(content)-400)
+	}
+
+	// the part we have is checked rather than the zip's copy of it
+	have := []byte(content[:400])
+	have[100] ^= 0xff
+
+	if err := archive.ResumeFrom("a.txt", ioutil.Discard, bytes.NewReader(have), 400, nil); !errors.Is(err, zip.ErrChecksum) {
+		t.Errorf("resuming from a corrupt part returned %v, want %v", err, zip.ErrChecksum)
+	}
+
+	// as is a download that's already complete
+	whole := []byte(content)
+	whole[900] ^= 0xff
+
+	if err := archive.ResumeFrom("a.txt", ioutil.Discard, bytes.NewReader(whole), uint64(len(whole)), nil); !errors.Is(err, zip.ErrChecksum) {
+		t.Errorf("resuming a complete but corrupt download returned %v, want %v", err, zip.ErrChecksum)
+	}
+}
+
+func TestExtractSkipVerify(t *testing.T) {
+	archive := newCorruptArchive(t, Options{SkipVerify: true}, "a.txt", strings.Repeat("0123456789", 100))
+
+	if err := archive.Extract("a.txt", ioutil.Discard); err != nil {
+		t.Errorf("extracting a corrupt file with SkipVerify returned %v", err)
+	}
+}
+
+func TestExtractProgress(t *testing.T) {
+	content := strings.Repeat("0123456789", defaultBufferSize/4)
+	archive := newTestArchive(t, Options{}, testFile{name: "a.txt", content: content})
+
+	for _, offset := range []uint64{0, 1000} {
+		var calls int
+		var last uint64
+
+		progress := func(downloaded, total uint64) {
+			calls++
+
+			if total != uint64(len(content)) {
+				t.Errorf("progress total is %d, want %d", total, len(content))
+			}
+
+			if downloaded <= last || downloaded > total {
+				t.Errorf("progress went from %d to %d of %d", last, downloaded, total)
+			}
+
+			last = downloaded
+		}
+
+		if err := archive.ExtractFrom("a.txt", ioutil.Discard, offset, progress); err != nil {
+			t.Fatal(err)
+		}
+
+		if calls < 2 {
+			t.Errorf("progress was called %d times from %d, want at least 2", calls, offset)
+		}
+
+		if last != uint64(len(content)) {
+			t.Errorf("progress finished at %d from %d, want %d", last, offset, len(content))
+		}
+	}
+}
+
+// an io.ReaderAt which fails reads of anything from failAt on with
+// io.ErrUnexpectedEOF, like a dropped connection, the first failures times
+type flakyReaderAt struct {
+	r        io.ReaderAt
+	failAt   int64
+	failures int
+}
+
+func (f *flakyReaderAt) ReadAt(buf []byte, off int64) (int, error) {
+	if f.failures > 0 && off+int64(len(buf)) > f.failAt {
+		f.failures--
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	return f.r.ReadAt(buf, off)
+}
+
+func TestExtractRetriesReads(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	data := makeZip(t, testFile{name: "a.txt", content: content})
+	reader := &flakyReaderAt{r: bytes.NewReader(data)}
+
+	var retries int
+
+	archive := openZip(t, Options{Retries: 3, OnRetry: func(error, time.Duration) { retries++ }}, reader, int64(len(data)))
+
+	offset, err := archive.DataOffset("a.txt")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader.failAt, reader.failures = offset+int64(len(content))/2, 2
+
+	var buf bytes.Buffer
+
+	if err := archive.Extract("a.txt", &buf); err != nil {
+		t.Fatalf("extracting with reads failing twice returned %v", err)
+	}
+
+	if buf.String() != content || retries != 2 {
+		t.Errorf("extracted %d bytes after %d retries, want %d after 2", buf.Len(), retries, len(content))
+	}
+}
+
+func TestExtractTruncatedNotRetried(t *testing.T) {
+	var buf bytes.Buffer
+
+	w := zip.NewWriter(&buf)
+	fw, err := w.Create("a.txt")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// random data doesn't compress, so cutting the compressed data short
+	// cuts the deflate stream short
+	random := make([]byte, 10000)
+	rand.New(rand.NewSource(1)).Read(random)
+	fw.Write(random)
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// halve the compressed size in the central directory, which is what the
+	// file is read by
+	data := buf.Bytes()
+	header := bytes.LastIndex(data, []byte("PK\x01\x02"))
+	size := binary.LittleEndian.Uint32(data[header+20:])
+	binary.LittleEndian.PutUint32(data[header+20:], size/2)
+
+	var retries int
+
+	archive := openZip(t, Options{Retries: 3, OnRetry: func(error, time.Duration) { retries++ }}, bytes.NewReader(data), int64(len(data)))
+
+	if err := archive.Extract("a.txt", ioutil.Discard); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("extracting a truncated file returned %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	if retries != 0 {
+		t.Errorf("extracting a truncated file was retried %d times", retries)
+	}
+}
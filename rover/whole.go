@@ -0,0 +1,53 @@
+package rover
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// OpenWhole is like OpenContext, but for servers which don't support range
+// requests: the whole zip is downloaded to temp with a single GET, and read
+// from there. the caller creates temp, and removes it once it's done with the
+// archive. Options.Timeout isn't applied, as the download may take any time
+func OpenWhole(ctx context.Context, rawURL string, opts Options, temp *os.File) (*Archive, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	downloadURL, err := parseURL(rawURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL.String(), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Transport: opts.Transport}
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to download zip: %s", resp.Status)
+	}
+
+	size, err := io.Copy(temp, resp.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to download zip: %w", err)
+	}
+
+	return newArchive(ctx, rawURL, opts, temp, size, temp)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/AmesianX/rover/rover"
+)
+
+const (
+	maxSuggestions = 5
+
+	// longer base names are only compared by substring, keeping the edit
+	// distance cheap however large the zip is
+	maxSuggestionLength = 64
+)
+
+// returns up to maxSuggestions names of entries which look like what name
+// was meant to be: those whose base name contains it (ignoring case) or is
+// only a few edits away from it
+func suggest(name string, entries []rover.ZipEntry) []string {
+	base := strings.ToLower(path.Base(name))
+
+	// allow roughly one typo in every four characters
+	threshold := len(base)/4 + 1
+
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	var suggestions []suggestion
+
+	for _, entry := range entries {
+		if entry.IsDir() != isDirectory(name) {
+			continue
+		}
+
+		candidate := strings.ToLower(path.Base(entry.Name))
+		distance := threshold + 1
+
+		if len(base) >= 3 && strings.Contains(candidate, base) {
+			distance = threshold
+		}
+
+		if len(base) <= maxSuggestionLength && len(candidate) <= maxSuggestionLength && abs(len(base)-len(candidate)) < distance {
+			if d := levenshtein(base, candidate); d < distance {
+				distance = d
+			}
+		}
+
+		if distance <= threshold {
+			suggestions = append(suggestions, suggestion{entry.Name, distance})
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].distance < suggestions[j].distance
+	})
+
+	var names []string
+
+	for i := 0; i < len(suggestions) && i < maxSuggestions; i++ {
+		names = append(names, suggestions[i].name)
+	}
+
+	return names
+}
+
+// returns the number of single byte insertions, deletions and substitutions
+// needed to turn a into b
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = previous[j-1] + cost
+
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
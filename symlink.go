@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/AmesianX/rover/rover"
+)
+
+// symlink targets are short, anything longer isn't really a symlink
+const maxSymlinkTarget = 4096
+
+var errBadSymlink = errors.New("invalid symlink")
+
+// reports whether file should be recreated as a symlink rather than written
+// as a file holding the path it points to
+func isSymlink(file rover.ZipEntry) bool {
+	if file.Mode&os.ModeSymlink == 0 || noSymlinks {
+		return false
+	}
+
+	if runtime.GOOS == "windows" {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "Warning: %s is a symlink, writing its target to a file instead\n", file.Name)
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// recreates the symlink file as outputFile, returning the length of its target.
+// unless -unsafe-links is given, targets outside the output directory are
+// refused so a zip can't be used to reach files elsewhere
+func extractSymlink(archive *rover.Archive, file rover.ZipEntry, outputFile string) (uint64, error) {
+	if file.UncompressedSize > maxSymlinkTarget {
+		return 0, fmt.Errorf("%w: target is too long (%d bytes)", errBadSymlink, file.UncompressedSize)
+	}
+
+	var target bytes.Buffer
+
+	if err := archive.Extract(file.Name, &target); err != nil {
+		return 0, err
+	}
+
+	if !unsafeLinks && !isWithin(outputFile, target.String()) {
+		return 0, fmt.Errorf("%w: link to %s (use -unsafe-links to allow it)", errUnsafePath, target.String())
+	}
+
+	// the link is made alongside and renamed into place, like files are
+	tempName := outputFile + ".rover-tmp"
+
+	os.Remove(tempName)
+
+	if err := os.Symlink(target.String(), tempName); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tempName, outputFile); err != nil {
+		os.Remove(tempName)
+		return 0, err
+	}
+
+	return uint64(target.Len()), nil
+}
+
+// reports whether target, a symlink target, stays within the output directory
+// when the symlink is at outputFile. links already in the output directory
+// are followed, so one can't be used to reach past it
+func isWithin(outputFile, target string) bool {
+	if filepath.IsAbs(target) || strings.HasPrefix(target, "/") {
+		return false
+	}
+
+	// a .. after a name goes back from wherever that name leads, which may
+	// be a link, so only leading ..s can be followed here
+	var named bool
+
+	for _, part := range strings.Split(filepath.ToSlash(target), "/") {
+		if part == ".." && named {
+			return false
+		}
+
+		named = named || (part != ".." && part != "." && part != "")
+	}
+
+	base := outputDir
+
+	if base == "" {
+		base = "."
+	}
+
+	base, err := realPath(base)
+
+	if err != nil {
+		return false
+	}
+
+	dir, err := realPath(filepath.Dir(outputFile))
+
+	if err != nil {
+		return false
+	}
+
+	resolved := filepath.Join(dir, filepath.FromSlash(target))
+
+	rel, err := filepath.Rel(base, resolved)
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// returns the absolute path of dir with any symlinks in it followed
+func realPath(dir string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(dir)
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Abs(resolved)
+}
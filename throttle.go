@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// the most written at once by a throttledWriter, so the rate stays smooth
+const maxThrottledWrite = 32 * 1024
+
+// a writer which paces writes to a number of bytes per second, giving up on
+// waiting once ctx is done
+type throttledWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	limiter *rate.Limiter
+}
+
+func newThrottledWriter(ctx context.Context, w io.Writer, bytesPerSecond uint64) *throttledWriter {
+	burst := maxThrottledWrite
+
+	if bytesPerSecond < uint64(burst) {
+		burst = int(bytesPerSecond)
+	}
+
+	return &throttledWriter{
+		ctx:     ctx,
+		w:       w,
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), burst),
+	}
+}
+
+func (t *throttledWriter) Write(buf []byte) (int, error) {
+	written := 0
+
+	for len(buf) > 0 {
+		n := len(buf)
+
+		if n > t.limiter.Burst() {
+			n = t.limiter.Burst()
+		}
+
+		if err := t.limiter.WaitN(t.ctx, n); err != nil {
+			return written, err
+		}
+
+		m, err := t.w.Write(buf[:n])
+		written += m
+
+		if err != nil {
+			return written, err
+		}
+
+		buf = buf[n:]
+	}
+
+	return written, nil
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// builds the transport every request is made with, as configured by the flags
+func newTransport() http.RoundTripper {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.Proxy = proxyFunc
+
+	// -t covers the whole of each request, this just connecting
+	base.DialContext = (&net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+
+	// each file read from the zip takes range requests of its own, so
+	// connections are kept around for the next one
+	base.IdleConnTimeout = keepaliveIdle
+	base.MaxIdleConnsPerHost = maxIdleConns
+	base.DisableKeepAlives = noKeepalive
+
+	if verbose {
+		base.Proxy = reportProxy(proxyFunc)
+	}
+
+	var transport http.RoundTripper = &retryTransport{
+		next:    base,
+		retries: retries,
+		delay:   retryDelay,
+	}
+
+	// credentials are only sent to the server the zip is on, not any it
+	// redirects to, unless -location-trusted says otherwise
+	var hosts map[string]bool
+
+	if !trustRedirect {
+		hosts = authHosts()
+	}
+
+	// there's always a User-Agent, if only an empty one
+	transport = &headerTransport{
+		next:    transport,
+		headers: requestHeaders,
+		hosts:   hosts,
+	}
+
+	// basic authentication is applied first, so an Authorization header
+	// given with -H takes precedence over it
+	if username != "" {
+		transport = &basicAuthTransport{
+			next:     transport,
+			username: username,
+			password: password,
+			hosts:    hosts,
+		}
+	}
+
+	return transport
+}
+
+// returns the hosts credentials are sent to: that of -u, or those of the URLs
+// in a -batch file
+func authHosts() map[string]bool {
+	hosts := make(map[string]bool)
+	urls := []string{sourceURL}
+
+	for _, job := range batchJobs {
+		urls = append(urls, job.url)
+	}
+
+	for _, rawURL := range urls {
+		if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+			hosts[strings.ToLower(u.Host)] = true
+		}
+	}
+
+	return hosts
+}
+
+// reports whether credentials can be sent with req, which they can if it's to
+// one of hosts, or hosts is nil
+func trustedHost(hosts map[string]bool, req *http.Request) bool {
+	return hosts == nil || hosts[strings.ToLower(req.URL.Host)]
+}
+
+// an http.RoundTripper which adds basic authentication to every request to
+// hosts, or to every request at all if hosts is nil
+type basicAuthTransport struct {
+	next     http.RoundTripper
+	username string
+	password string
+	hosts    map[string]bool
+}
+
+func (t *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !trustedHost(t.hosts, req) {
+		return t.next.RoundTrip(req)
+	}
+
+	// a RoundTripper mustn't modify the request it's given
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(t.username, t.password)
+
+	return t.next.RoundTrip(req)
+}
+
+// wraps proxy, an http.Transport.Proxy function, to say which proxy is used
+// the first time it's asked
+func reportProxy(proxy func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	var once sync.Once
+
+	return func(req *http.Request) (*url.URL, error) {
+		var proxyURL *url.URL
+		var err error
+
+		if proxy != nil {
+			proxyURL, err = proxy(req)
+		}
+
+		once.Do(func() {
+			if proxyURL != nil {
+				fmt.Fprintf(os.Stderr, "Using proxy %s\n", proxyURL.Redacted())
+			} else if err == nil {
+				fmt.Fprintln(os.Stderr, "Not using a proxy")
+			}
+		})
+
+		return proxyURL, err
+	}
+}
+
+// parses a proxy URL into a function suitable for http.Transport.Proxy. an
+// empty URL means no proxy at all
+func parseProxy(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	if proxy == "" {
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// net/http supports socks5 proxies itself, resolving names through the
+	// proxy with either scheme
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("%s: scheme must be http, https, socks5 or socks5h", proxy)
+	}
+
+	return http.ProxyURL(proxyURL), nil
+}
+
+// headers which carry credentials, so are only sent to trusted hosts
+var credentialHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+}
+
+// an http.RoundTripper which adds a set of headers to every request, apart
+// from those carrying credentials to anywhere but hosts (unless it's nil)
+type headerTransport struct {
+	next    http.RoundTripper
+	headers http.Header
+	hosts   map[string]bool
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trusted := trustedHost(t.hosts, req)
+
+	req = req.Clone(req.Context())
+
+	for name, values := range t.headers {
+		if trusted || !credentialHeaders[name] {
+			req.Header[name] = values
+		}
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// parses a curl style "Name: Value" header
+func parseHeader(header string) (string, string, error) {
+	parts := strings.SplitN(header, ":", 2)
+
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("%q must be of the form \"Name: Value\"", header)
+	}
+
+	name := strings.TrimSpace(parts[0])
+
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("%q isn't a valid header name", parts[0])
+	}
+
+	return http.CanonicalHeaderKey(name), strings.TrimSpace(parts[1]), nil
+}
+
+// an http.RoundTripper which retries idempotent requests failing with a
+// network error or a 502, 503 or 504, doubling the delay (plus up to half
+// again, at random) between each attempt
+type retryTransport struct {
+	next    http.RoundTripper
+	retries int
+	delay   time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	delay := t.delay
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+
+		if attempt >= t.retries || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+			return resp, err
+		}
+
+		if err != nil && !isRetryable(err) {
+			return resp, err
+		}
+
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			return resp, err
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		// jitter stops a crowd of clients retrying in lockstep
+		wait := delay
+
+		if delay > 0 {
+			wait += time.Duration(rand.Int63n(int64(delay)/2 + 1))
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		delay *= 2
+	}
+}
+
+// reports whether err is a transient failure worth retrying: the connection
+// being dropped, reset or timing out
+func isRetryable(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// reports whether a response with status code is worth retrying, as the
+// server or a gateway in front of it is having trouble
+func isRetryableStatus(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AmesianX/rover/rover"
+	"github.com/dustin/go-humanize"
+)
+
+// a file or directory in the -tree listing
+type treeNode struct {
+	name     string
+	isDir    bool
+	files    int    // files in it and below it, for a directory
+	size     uint64 // total size of those files, or of the file itself
+	children []*treeNode
+	byName   map[string]*treeNode // children, so directories are merged
+}
+
+// returns the child of n called name, adding it if there isn't one yet
+func (n *treeNode) child(name string, isDir bool) *treeNode {
+	if c, ok := n.byName[name]; ok {
+		// a broken zip may have a file and a directory of the same name
+		if isDir && !c.isDir {
+			c.isDir, c.size, c.byName = true, 0, make(map[string]*treeNode)
+		}
+
+		return c
+	}
+
+	c := &treeNode{name: name, isDir: isDir}
+
+	if isDir {
+		c.byName = make(map[string]*treeNode)
+	}
+
+	n.children = append(n.children, c)
+	n.byName[name] = c
+
+	return c
+}
+
+// builds a tree of files from their paths, with directories given explicitly
+// in the zip merged with those only implied by the paths of their files
+func buildTree(files []rover.ZipEntry) *treeNode {
+	root := &treeNode{isDir: true, byName: make(map[string]*treeNode)}
+
+	for _, f := range files {
+		segments := strings.FieldsFunc(f.Name, func(r rune) bool { return r == '/' })
+
+		if len(segments) == 0 {
+			continue
+		}
+
+		dirs := segments
+
+		if !f.IsDir() {
+			dirs = segments[:len(segments)-1]
+		}
+
+		// each directory on the way counts the file
+		node := root
+
+		for _, dir := range dirs {
+			if !f.IsDir() {
+				node.files++
+				node.size += f.UncompressedSize
+			}
+
+			node = node.child(dir, true)
+		}
+
+		if !f.IsDir() {
+			node.files++
+			node.size += f.UncompressedSize
+
+			node.child(segments[len(segments)-1], false).size = f.UncompressedSize
+		}
+	}
+
+	return root
+}
+
+// prints files to stdout as a tree of directories, showing how many files
+// each holds and their total size. depth limits how many levels are shown, 0
+// means no limit
+func listFilesTree(files []rover.ZipEntry, depth int) error {
+	root := buildTree(files)
+
+	if _, err := fmt.Printf(".  (%s)\n", treeSummary(root)); err != nil {
+		return err
+	}
+
+	return printTree(root, "", 1, depth)
+}
+
+// prints the children of n, each line starting with prefix
+func printTree(n *treeNode, prefix string, level, depth int) error {
+	for i, c := range n.children {
+		branch, indent := "├── ", "│   "
+
+		if i == len(n.children)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		line := c.name + "  (" + humanize.Bytes(c.size) + ")"
+
+		if c.isDir {
+			line = c.name + "/  (" + treeSummary(c) + ")"
+		}
+
+		if _, err := fmt.Printf("%s%s%s\n", prefix, branch, line); err != nil {
+			return err
+		}
+
+		if c.isDir && (depth == 0 || level < depth) {
+			if err := printTree(c, prefix+indent, level+1, depth); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// returns how many files a directory holds and their total size
+func treeSummary(n *treeNode) string {
+	if n.files == 1 {
+		return "1 file, " + humanize.Bytes(n.size)
+	}
+
+	return fmt.Sprintf("%d files, %s", n.files, humanize.Bytes(n.size))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/AmesianX/rover/rover"
+)
+
+// reads every file in files from the zip without writing it anywhere, so each
+// is checked against its CRC32, until ctx is done. like unzip -t, each file is
+// reported as OK or FAIL (failures on stderr), carrying on past failures, and
+// followed by a summary. files without a CRC32 to check against are reported
+// as SKIP. returns the exit code of the first to fail, or 0 if none did
+func verifyFiles(ctx context.Context, archive *rover.Archive, files []rover.ZipEntry) int {
+	var pending []rover.ZipEntry
+
+	for _, f := range files {
+		if !f.IsDir() {
+			pending = append(pending, f)
+		}
+	}
+
+	var failed, skipped int
+
+	status := 0
+
+	for i, f := range pending {
+		if ctx.Err() != nil {
+			return exitFailure
+		}
+
+		// the progress bar doesn't say which file it's for
+		if verbose {
+			fmt.Fprintf(messages, "(%d/%d) %s\n", i+1, len(pending), f.Name)
+		}
+
+		// some zips written as a stream record a CRC32 of zero, though it's
+		// also that of an empty file
+		if f.CRC32 == 0 && f.UncompressedSize > 0 {
+			fmt.Fprintf(messages, "SKIP  %s: no CRC32 in the zip to check it against\n", f.Name)
+			skipped++
+			continue
+		}
+
+		// encrypted files can't be read at all
+		err := rover.ErrEncrypted
+
+		if !f.Encrypted {
+			_, err = downloadFile(archive, f, nil, 0, f.Name)
+		}
+
+		if errors.Is(err, context.Canceled) {
+			return exitFailure
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "FAIL  %s: %v\n", f.Name, err)
+			failed++
+
+			if status == 0 {
+				status = exitStatus(err)
+			}
+
+			continue
+		}
+
+		fmt.Fprintf(messages, "OK    %s\n", f.Name)
+	}
+
+	summary := fmt.Sprintf("%s checked, %s", plural(len(pending)-skipped, "file", "files"), plural(failed, "error", "errors"))
+
+	if skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", skipped)
+	}
+
+	fmt.Fprintln(messages, summary)
+
+	return status
+}